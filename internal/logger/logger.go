@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/0x4d31/galah/internal/suppress"
 	"github.com/0x4d31/galah/pkg/enrich"
 	"github.com/0x4d31/galah/pkg/llm"
 	"github.com/google/uuid"
@@ -24,8 +25,10 @@ const (
 	errorContentGeneration   = "contentGenerationError"
 )
 
-// New creates a new Logger instance with the specified configuration.
-func New(eventLogFile string, modelConfig llm.Config, eCache *enrich.Enricher, sessionizer *Sessionizer, l *logrus.Logger) (*Logger, error) {
+// New creates a new Logger instance with the specified configuration. When
+// otlpConfig.Enabled is set, events are dual-shipped to an OTLP/HTTP log
+// collector in addition to the file-based EventLogger.
+func New(eventLogFile string, modelConfig llm.Config, otlpConfig OTLPConfig, suppressor *suppress.Suppressor, eCache *enrich.Enricher, sessionizer *Sessionizer, l *logrus.Logger) (*Logger, error) {
 	eventLogger := logrus.New()
 	eventLogger.SetFormatter(&logrus.JSONFormatter{
 		TimestampFormat: time.RFC3339,
@@ -39,18 +42,34 @@ func New(eventLogFile string, modelConfig llm.Config, eCache *enrich.Enricher, s
 	}
 	eventLogger.Out = evFile
 
+	otlpExporter, err := NewOTLPExporter(otlpConfig, l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up OTLP exporter: %w", err)
+	}
+
 	return &Logger{
 		EnrichCache: eCache,
 		Sessionizer: sessionizer,
 		EventLogger: eventLogger,
 		LLMConfig:   modelConfig,
 		Logger:      l,
+		otlp:        otlpExporter,
+		suppressor:  suppressor,
 	}, nil
 }
 
+// Close releases resources held by the Logger, flushing any pending OTLP
+// batch before returning.
+func (l *Logger) Close() {
+	l.otlp.Close()
+}
+
 // LogError logs a failedResponse event.
 func (l *Logger) LogError(r *http.Request, resp, port string, err error) {
 	fields := l.commonFields(r, port)
+	if !l.allowed(fields) {
+		return
+	}
 	errorFields := errorFields(err, resp)
 	for k, v := range errorFields {
 		fields[k] = v
@@ -60,11 +79,15 @@ func (l *Logger) LogError(r *http.Request, resp, port string, err error) {
 	fields["response.metadata.temperature"] = l.LLMConfig.Temperature
 
 	l.EventLogger.WithFields(fields).Error("failedResponse: returned 500 internal server error")
+	l.otlp.Enqueue(fields, "failedResponse: returned 500 internal server error", logrus.ErrorLevel)
 }
 
 // LogEvent logs a successfulResponse event.
 func (l *Logger) LogEvent(r *http.Request, resp llm.JSONResponse, port, respSource string) {
 	fields := l.commonFields(r, port)
+	if !l.allowed(fields) {
+		return
+	}
 
 	// Flatten response headers
 	for k, v := range resp.Headers {
@@ -79,6 +102,71 @@ func (l *Logger) LogEvent(r *http.Request, resp llm.JSONResponse, port, respSour
 	fields["response.metadata.temperature"] = l.LLMConfig.Temperature
 
 	l.EventLogger.WithFields(fields).Info("successfulResponse")
+	l.otlp.Enqueue(fields, resp.Body, logrus.InfoLevel)
+}
+
+// wsFrameLogThreshold bounds how large a websocket frame payload we log
+// inline; anything larger is logged by hash only.
+const wsFrameLogThreshold = 2048
+
+// LogWSFrame logs a single websocket frame through the same commonFields/
+// allowed/otlp pipeline as LogEvent and LogError, so frame events get
+// enrichment tags, suppression sampling, and OTLP export like any other
+// event, tagged with sessionID so a full ws session is reconstructable.
+func (l *Logger) LogWSFrame(r *http.Request, port, sessionID, direction, opcode string, payload []byte) {
+	fields := l.commonFields(r, port)
+	if !l.allowed(fields) {
+		return
+	}
+	fields["session"] = sessionID
+
+	hash := sha256.Sum256(payload)
+	fields["ws.direction"] = direction
+	fields["ws.opcode"] = opcode
+	fields["ws.size"] = len(payload)
+	fields["ws.payloadSha256"] = hex.EncodeToString(hash[:])
+	if len(payload) <= wsFrameLogThreshold {
+		fields["ws.payload"] = string(payload)
+	}
+
+	l.EventLogger.WithFields(fields).Info("websocketFrame")
+	l.otlp.Enqueue(fields, "websocketFrame", logrus.InfoLevel)
+}
+
+// SetSuppressor swaps in a new Suppressor (e.g. after a SIGHUP config
+// reload), taking effect for allowed() on every subsequent call. Safe to call
+// concurrently with allowed().
+func (l *Logger) SetSuppressor(s *suppress.Suppressor) {
+	l.suppressorMu.Lock()
+	l.suppressor = s
+	l.suppressorMu.Unlock()
+}
+
+// allowed reports whether an event with the given flattened fields should be
+// logged, applying the suppress subsystem's per-(src_ip, tag) sampling so
+// scanner floods don't drown out interesting traffic. Events with no tag, or
+// when no Suppressor is configured, are always allowed.
+func (l *Logger) allowed(fields logrus.Fields) bool {
+	l.suppressorMu.Lock()
+	suppressor := l.suppressor
+	l.suppressorMu.Unlock()
+	if suppressor == nil {
+		return true
+	}
+
+	srcIP, _ := fields["src_ip"].(string)
+	tagsField, _ := fields["tags"].(string)
+	if tagsField == "" {
+		return true
+	}
+
+	for _, tag := range strings.Split(tagsField, ",") {
+		if !suppressor.Allow(srcIP, tag) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (l *Logger) commonFields(r *http.Request, port string) logrus.Fields {
@@ -0,0 +1,340 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// resourceAttributeKeys are promoted from commonFields to the OTLP resource,
+// rather than the individual log record, since they identify the sensor/session
+// rather than a single event.
+var resourceAttributeKeys = map[string]bool{
+	"src_ip":    true,
+	"dest_port": true,
+	"session":   true,
+}
+
+const (
+	defaultOTLPPath          = "/v1/logs"
+	defaultOTLPTimeout       = 10 * time.Second
+	defaultOTLPQueueSize     = 2048
+	defaultOTLPBatchSize     = 256
+	defaultOTLPFlushInterval = 5 * time.Second
+	defaultOTLPMaxRetries    = 5
+)
+
+// OTLPConfig configures the OTLP/HTTP log exporter.
+type OTLPConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Endpoint string            `yaml:"endpoint"`
+	Path     string            `yaml:"path"`
+	Headers  map[string]string `yaml:"headers"`
+	Timeout  time.Duration     `yaml:"timeout"`
+	Gzip     bool              `yaml:"gzip"`
+	Proxy    string            `yaml:"proxy"`
+	TLS      struct {
+		InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	} `yaml:"tls"`
+}
+
+// otlpEvent is the internal representation queued for export, built from the
+// same logrus.Fields we already flatten for the file-based EventLogger.
+type otlpEvent struct {
+	fields logrus.Fields
+	body   string
+	level  logrus.Level
+	ts     time.Time
+}
+
+// OTLPExporter batches LogEvent/LogError payloads and ships them to an
+// OpenTelemetry log collector over OTLP/HTTP, so operators can dual-ship
+// alongside the file-based EventLogger.
+type OTLPExporter struct {
+	cfg      OTLPConfig
+	endpoint string
+	client   *http.Client
+	logger   *logrus.Logger
+
+	queue chan otlpEvent
+
+	droppedMu sync.Mutex
+	dropped   int
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewOTLPExporter builds and starts an OTLPExporter. It returns (nil, nil) if
+// cfg.Enabled is false, so callers can unconditionally hold the result and
+// treat a nil exporter as "OTLP shipping disabled".
+func NewOTLPExporter(cfg OTLPConfig, l *logrus.Logger) (*OTLPExporter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("otlp: endpoint is required when otlp logging is enabled")
+	}
+	path := cfg.Path
+	if path == "" {
+		path = defaultOTLPPath
+	}
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/") + path
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultOTLPTimeout
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.TLS.InsecureSkipVerify}, //nolint:gosec // operator opt-in
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &OTLPExporter{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+		logger:   l,
+		queue:    make(chan otlpEvent, defaultOTLPQueueSize),
+		cancel:   cancel,
+	}
+
+	e.wg.Add(1)
+	go e.run(ctx)
+
+	return e, nil
+}
+
+// Enqueue queues a flattened event for export. If the queue is full the event
+// is dropped and counted, rather than blocking the honeypot on a slow or dead
+// downstream collector.
+func (e *OTLPExporter) Enqueue(fields logrus.Fields, body string, level logrus.Level) {
+	if e == nil {
+		return
+	}
+	select {
+	case e.queue <- otlpEvent{fields: fields, body: body, level: level, ts: time.Now()}:
+	default:
+		e.droppedMu.Lock()
+		e.dropped++
+		e.droppedMu.Unlock()
+	}
+}
+
+// Close flushes any pending events and stops the batching worker.
+func (e *OTLPExporter) Close() {
+	if e == nil {
+		return
+	}
+	e.cancel()
+	e.wg.Wait()
+}
+
+func (e *OTLPExporter) run(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(defaultOTLPFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpEvent, 0, defaultOTLPBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		e.export(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case ev := <-e.queue:
+			batch = append(batch, ev)
+			if len(batch) >= defaultOTLPBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			e.reportDropped()
+		case <-ctx.Done():
+			// Drain whatever is already queued before exiting.
+			for {
+				select {
+				case ev := <-e.queue:
+					batch = append(batch, ev)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) reportDropped() {
+	e.droppedMu.Lock()
+	n := e.dropped
+	e.dropped = 0
+	e.droppedMu.Unlock()
+	if n > 0 {
+		e.logger.Errorf("otlp exporter: dropped %d events because the export queue was full", n)
+	}
+}
+
+func (e *OTLPExporter) export(batch []otlpEvent) {
+	// Each event gets its own ResourceLogs entry: the resource attributes
+	// (resourceAttributeKeys plus every request.* field) describe a single request, not
+	// the whole batch, so merging them into one shared Resource would
+	// misattribute every record but the last to one attacker's session.
+	resourceLogs := make([]*logspb.ResourceLogs, 0, len(batch))
+	for _, ev := range batch {
+		rec, resourceAttrs := toLogRecord(ev)
+		resourceLogs = append(resourceLogs, &logspb.ResourceLogs{
+			Resource: &resourcepb.Resource{Attributes: toKeyValues(resourceAttrs)},
+			ScopeLogs: []*logspb.ScopeLogs{
+				{LogRecords: []*logspb.LogRecord{rec}},
+			},
+		})
+	}
+	req := &collectorpb.ExportLogsServiceRequest{ResourceLogs: resourceLogs}
+
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		e.logger.Errorf("otlp exporter: failed to marshal export request: %s", err)
+		return
+	}
+
+	if err := e.send(payload); err != nil {
+		e.logger.Errorf("otlp exporter: failed to export %d log record(s): %s", len(batch), err)
+	}
+}
+
+func toLogRecord(ev otlpEvent) (*logspb.LogRecord, map[string]string) {
+	resourceAttrs := map[string]string{}
+	attrs := map[string]string{}
+	for k, v := range ev.fields {
+		s := fmt.Sprintf("%v", v)
+		if resourceAttributeKeys[k] || strings.HasPrefix(k, "request.") {
+			resourceAttrs[k] = s
+			continue
+		}
+		attrs[k] = s
+	}
+
+	return &logspb.LogRecord{
+		TimeUnixNano:         uint64(ev.ts.UnixNano()),
+		ObservedTimeUnixNano: uint64(ev.ts.UnixNano()),
+		SeverityText:         ev.level.String(),
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: ev.body}},
+		Attributes:           toKeyValues(attrs),
+	}, resourceAttrs
+}
+
+func toKeyValues(m map[string]string) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+		})
+	}
+	return kvs
+}
+
+func (e *OTLPExporter) send(payload []byte) error {
+	contentEncoding := ""
+	body := payload
+	if e.cfg.Gzip {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(payload); err != nil {
+			return fmt.Errorf("gzip compress: %w", err)
+		}
+		if err := gw.Close(); err != nil {
+			return fmt.Errorf("gzip close: %w", err)
+		}
+		body = buf.Bytes()
+		contentEncoding = "gzip"
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < defaultOTLPMaxRetries; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		for k, v := range e.cfg.Headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			lastErr = err
+			time.Sleep(backoff(attempt))
+			continue
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+			lastErr = fmt.Errorf("collector returned %d", resp.StatusCode)
+			time.Sleep(retryAfterOrBackoff(resp.Header.Get("Retry-After"), attempt))
+		default:
+			return fmt.Errorf("collector returned %d", resp.StatusCode)
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", defaultOTLPMaxRetries, lastErr)
+}
+
+// backoff returns an exponential backoff duration with jitter for the given
+// (zero-indexed) retry attempt.
+func backoff(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt)) * 100 * time.Millisecond
+	if base > 10*time.Second {
+		base = 10 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) + 1))
+	return base/2 + jitter/2
+}
+
+// retryAfterOrBackoff honors a collector's Retry-After header (seconds) if
+// present and parseable, falling back to the standard backoff otherwise.
+func retryAfterOrBackoff(retryAfter string, attempt int) time.Duration {
+	if retryAfter == "" {
+		return backoff(attempt)
+	}
+	if secs, err := strconv.Atoi(retryAfter); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	return backoff(attempt)
+}
@@ -0,0 +1,227 @@
+// Package suppress filters scanner noise out of the event pipeline: a
+// drop list of ErrorLog patterns that should never be logged at all, and a
+// per-(src_ip, tag) sampler so a single scanner blasting the same tag
+// thousands of times produces one event per sample_rate occurrences instead
+// of every one of them.
+package suppress
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bucketTTL       = 5 * time.Minute
+	sweepInterval   = time.Minute
+	summaryInterval = time.Minute
+)
+
+// Rule is a single drop pattern matched against http.Server.ErrorLog lines.
+// Plain rules are matched as a substring; rules starting and ending with "/"
+// are compiled as a regexp.
+type Rule string
+
+// SampleRule samples events carrying the given tag down to one in every
+// Rate occurrences, per source IP.
+type SampleRule struct {
+	Tag  string `yaml:"tag"`
+	Rate int    `yaml:"sample_rate"`
+}
+
+// Config configures the suppress subsystem.
+type Config struct {
+	DropPatterns []Rule       `yaml:"drop_patterns"`
+	SampleRules  []SampleRule `yaml:"sample_rules"`
+}
+
+// Suppressor drops known-noisy ErrorLog lines and samples repetitive,
+// already-tagged events so scanner floods don't drown out interesting
+// traffic.
+type Suppressor struct {
+	dropLiteral []string
+	dropRegexp  []*regexp.Regexp
+	rates       map[string]int
+
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	counts  map[string]int
+
+	stop chan struct{}
+}
+
+// bucket tracks, per (src_ip, tag), how many events have been seen since the
+// bucket was created or last reset, so every `rate`-th event is let through.
+type bucket struct {
+	seen     int
+	lastSeen time.Time
+}
+
+// New builds a Suppressor from cfg and starts its background eviction and
+// summary-reporting goroutine. Call Close to stop it.
+func New(cfg Config, l *logrus.Logger) (*Suppressor, error) {
+	s := &Suppressor{
+		rates:   make(map[string]int, len(cfg.SampleRules)),
+		logger:  l,
+		buckets: make(map[string]*bucket),
+		counts:  make(map[string]int),
+		stop:    make(chan struct{}),
+	}
+
+	for _, p := range cfg.DropPatterns {
+		raw := string(p)
+		if strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") && len(raw) > 1 {
+			re, err := regexp.Compile(raw[1 : len(raw)-1])
+			if err != nil {
+				return nil, fmt.Errorf("suppress: invalid drop pattern %q: %w", raw, err)
+			}
+			s.dropRegexp = append(s.dropRegexp, re)
+			continue
+		}
+		s.dropLiteral = append(s.dropLiteral, raw)
+	}
+
+	for _, r := range cfg.SampleRules {
+		if r.Rate <= 0 {
+			r.Rate = 1
+		}
+		s.rates[r.Tag] = r.Rate
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Close stops the background eviction/summary goroutine.
+func (s *Suppressor) Close() {
+	close(s.stop)
+}
+
+// ShouldDropLine reports whether a server.ErrorLog line matches a configured
+// drop pattern and should be discarded entirely.
+func (s *Suppressor) ShouldDropLine(line string) bool {
+	for _, lit := range s.dropLiteral {
+		if strings.Contains(line, lit) {
+			return true
+		}
+	}
+	for _, re := range s.dropRegexp {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer returns an io.Writer suitable for http.Server.ErrorLog: lines
+// matching a drop pattern are discarded, everything else is forwarded to the
+// underlying logger.
+func (s *Suppressor) Writer() *errorLogWriter {
+	return &errorLogWriter{s: s}
+}
+
+type errorLogWriter struct{ s *Suppressor }
+
+func (w *errorLogWriter) Write(p []byte) (int, error) {
+	if w.s.ShouldDropLine(string(p)) {
+		return len(p), nil
+	}
+	w.s.logger.Errorf("%s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// Allow applies the 1-in-rate sampler for the given (srcIP, tag) pair: the
+// first event of a bucket and every rate-th one after it pass. It
+// returns true if the event should be logged, false if it should be
+// suppressed. Every tag without a configured SampleRule is always allowed.
+func (s *Suppressor) Allow(srcIP, tag string) bool {
+	rate, ok := s.rates[tag]
+	if !ok || tag == "" {
+		return true
+	}
+
+	key := srcIP + "|" + tag
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[key]++
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{lastSeen: time.Now()}
+		s.buckets[key] = b
+	}
+	b.lastSeen = time.Now()
+
+	allow := b.seen%rate == 0
+	b.seen++
+
+	return allow
+}
+
+func (s *Suppressor) run() {
+	evictTicker := time.NewTicker(sweepInterval)
+	summaryTicker := time.NewTicker(summaryInterval)
+	defer evictTicker.Stop()
+	defer summaryTicker.Stop()
+
+	for {
+		select {
+		case <-evictTicker.C:
+			s.evict()
+		case <-summaryTicker.C:
+			s.emitSummary()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Suppressor) evict() {
+	cutoff := time.Now().Add(-bucketTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// emitSummary logs a suppressed_summary event with per-tag counts so nothing
+// is silently lost, then resets the counters for the next interval.
+func (s *Suppressor) emitSummary() {
+	s.mu.Lock()
+	counts := s.counts
+	s.counts = make(map[string]int)
+	s.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+
+	byTag := make(map[string]int)
+	for key, n := range counts {
+		parts := strings.SplitN(key, "|", 2)
+		tag := key
+		if len(parts) == 2 {
+			tag = parts[1]
+		}
+		byTag[tag] += n
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"event": "suppressed_summary",
+		"tags":  byTag,
+	}).Info("suppressed_summary")
+}
@@ -2,17 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/0x4d31/galah/internal/suppress"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -32,12 +36,18 @@ var ignoreHeaders = map[string]bool{
 }
 
 func (app *App) startServers() error {
-	var g errgroup.Group
 	app.Servers = make(map[uint16]*http.Server)
+	return app.startServerSet(app.Config.Ports)
+}
 
-	mu := sync.Mutex{}
+// startServerSet starts the given set of ports, following the same
+// errgroup fan-out as startServers, and records each one in app.Servers.
+// It's shared between the initial startup path and the SIGHUP reload path,
+// which only needs to start the ports that were added by the reload.
+func (app *App) startServerSet(ports []PortConfig) error {
+	var g errgroup.Group
 
-	for _, pc := range app.Config.Ports {
+	for _, pc := range ports {
 		pc := pc // Capture the loop variable
 		g.Go(func() error {
 			server := app.setupServer(pc)
@@ -52,14 +62,14 @@ func (app *App) startServers() error {
 				err = fmt.Errorf("unknown protocol for port %d", pc.Port)
 			}
 
-			if err != nil {
+			if err != nil && err != http.ErrServerClosed {
 				logger.Errorf("error starting server on port %d: %s", pc.Port, err)
 				return err
 			}
 
-			mu.Lock()
+			app.serversMu.Lock()
 			app.Servers[pc.Port] = server
-			mu.Unlock()
+			app.serversMu.Unlock()
 
 			return nil
 		})
@@ -77,6 +87,21 @@ func (app *App) setupServer(pc PortConfig) *http.Server {
 		}),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		// Requests derive their context from app.ctx, which is cancelled as
+		// soon as a shutdown signal is received, so a long-running LLM call
+		// is cancelled instead of racing the drain deadline.
+		BaseContext: func(net.Listener) context.Context {
+			return app.ctx
+		},
+	}
+
+	app.suppressorMu.Lock()
+	suppressor := app.Suppressor
+	app.suppressorMu.Unlock()
+	if suppressor != nil {
+		// Drop known-noisy lines (TLS handshake failures, malformed HTTP
+		// prefaces, etc.) before they ever reach the logger.
+		server.ErrorLog = log.New(suppressor.Writer(), "", 0)
 	}
 
 	return server
@@ -88,7 +113,23 @@ func (app *App) startTLSServer(server *http.Server, pc PortConfig) error {
 	}
 
 	tlsConfig, ok := app.Config.TLS[pc.TLSProfile]
-	if !ok || tlsConfig.Certificate == "" || tlsConfig.Key == "" {
+	if !ok {
+		return fmt.Errorf("TLS profile %q is not configured for port %d", pc.TLSProfile, pc.Port)
+	}
+
+	if tlsConfig.Autogen {
+		logger.Infof("starting HTTPS server on port %d with autogen TLS profile: %s", pc.Port, pc.TLSProfile)
+		server.TLSConfig = &tls.Config{
+			GetCertificate: app.getCertificateForClientHello(pc.TLSProfile),
+		}
+		err := server.ListenAndServeTLS("", "")
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if tlsConfig.Certificate == "" || tlsConfig.Key == "" {
 		return fmt.Errorf("TLS profile is incomplete for port %d", pc.Port)
 	}
 
@@ -100,6 +141,48 @@ func (app *App) startTLSServer(server *http.Server, pc PortConfig) error {
 	return nil
 }
 
+// getCertificateForClientHello returns a GetCertificate callback bound to the
+// listening port's own TLS profile (fallbackProfile), so it resolves the
+// right profile even though the same *App serves multiple ports. It picks
+// the profile whose SANs match the ClientHello's SNI, letting a single
+// listener serve multiple autogen profiles chosen by ServerName. If the
+// requested name doesn't match any profile (or no SNI was sent), the port's
+// own configured profile is used as a fallback.
+func (app *App) getCertificateForClientHello(fallbackProfile string) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		app.autogenOnce.Do(func() {
+			dir := app.Config.TLSAutogenDir
+			if dir == "" {
+				dir = "autogen"
+			}
+			app.autogenState = newAutogenState(dir)
+		})
+
+		if hello.ServerName != "" {
+			for name, tp := range app.Config.TLS {
+				if tp.Autogen && matchesAnySAN(hello.ServerName, tp) {
+					return app.autogenState.certForProfile(name, tp)
+				}
+			}
+		}
+
+		tp, ok := app.Config.TLS[fallbackProfile]
+		if !ok || !tp.Autogen {
+			return nil, fmt.Errorf("no autogen TLS profile matches ServerName %q", hello.ServerName)
+		}
+		return app.autogenState.certForProfile(fallbackProfile, tp)
+	}
+}
+
+func matchesAnySAN(serverName string, tp TLSProfile) bool {
+	for _, dns := range tp.DNSNames {
+		if dns == serverName {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *App) startHTTPServer(server *http.Server, pc PortConfig) error {
 	logger.Infof("starting HTTP server on port %d", pc.Port)
 	err := server.ListenAndServe()
@@ -110,40 +193,82 @@ func (app *App) startHTTPServer(server *http.Server, pc PortConfig) error {
 }
 
 func (app *App) handleRequest(w http.ResponseWriter, r *http.Request, serverAddr string) {
+	app.inFlight.Add(1)
+	atomic.AddInt64(&app.inFlightCount, 1)
+	defer func() {
+		atomic.AddInt64(&app.inFlightCount, -1)
+		app.inFlight.Done()
+	}()
+
 	_, port, err := net.SplitHostPort(serverAddr)
 	if err != nil {
 		port = ""
 	}
 
 	logger.Infof("port %s received a request for %q, from source %s", port, r.URL.String(), r.RemoteAddr)
+
+	if isWebSocketUpgrade(r) {
+		app.handleWebSocket(w, r, port)
+		return
+	}
+
 	// Check the response cache
+	streamed := false
 	response, err := app.checkCache(r, port)
 	if err != nil {
 		logger.Infof("request cache miss for %q: %s", r.URL.String(), err)
-		// Call the LLM API to generate response
-		responseString, err := app.generateLLMResponse(r)
-		if err != nil {
-			logger.Errorf("error generating response: %s", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		logger.Infof("generated HTTP response: %s", responseString)
 
-		// Store the generated response in the cache
-		response = []byte(responseString)
-		key := getCacheKey(r, port)
-		err = app.storeResponse(key, response)
+		if app.supportsStreaming() {
+			key := getCacheKey(r, port)
+			var headersSent bool
+			response, headersSent, err = app.streamLLMResponse(r.Context(), w, r, port, key)
+			if err != nil {
+				logger.Errorf("error streaming response: %s", err)
+				if !headersSent {
+					// Nothing was ever written to w, so it's still safe to
+					// send a normal error response instead of leaving the
+					// client with an implicit 200 OK and empty body.
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+			streamed = true
+		} else {
+			// Call the LLM API to generate response, cancelling it if the
+			// server is shutting down rather than racing the drain deadline.
+			responseString, err := app.generateLLMResponse(r.Context(), r)
+			if err != nil {
+				logger.Errorf("error generating response: %s", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			logger.Infof("generated HTTP response: %s", responseString)
+
+			// Store the generated response in the cache
+			response = []byte(responseString)
+			key := getCacheKey(r, port)
+			err = app.storeResponse(key, response)
+		}
 	}
 
-	// Parse the JSON-encoded data into a HTTPResponse struct, and send it to the client.
+	// Parse the JSON-encoded data into a HTTPResponse struct, used below for
+	// logging (and, if we haven't already streamed the response, for sending
+	// it to the client).
 	var respData HTTPResponse
 	if err := json.Unmarshal(response, &respData); err != nil {
 		logger.Errorf("error unmarshalling the json-encoded data: %s", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		if !streamed {
+			// Only safe pre-stream: once streamed is true, headers and body
+			// chunks are already flushed to the client, so writing an error
+			// status/body here would corrupt the open chunked response.
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	sendResponse(w, respData)
+	if !streamed {
+		sendResponse(w, respData)
+	}
 	logger.Infof("sent the generated response to %s", r.RemoteAddr)
 
 	// The response headers are logged exactly as generated by OpenAI, however,
@@ -169,21 +294,192 @@ func isExcludedHeader(headerKey string) bool {
 	return ignoreHeaders[strings.ToLower(headerKey)]
 }
 
-func (app *App) listenForShutdownSignals(ctx context.Context) {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+// defaultShutdownDrainTimeout is used when Config.ShutdownDrainTimeout is
+// unset. It's the deadline given to http.Server.Shutdown before falling back
+// to a hard server.Close().
+const defaultShutdownDrainTimeout = 30 * time.Second
 
-	go func() {
-		<-sig
-		logger.Infof("received shutdown signal. shutting down servers...")
+// listenForShutdownSignals handles SIGINT/SIGTERM by draining in-flight
+// requests and shutting down all servers, and SIGHUP by reloading
+// Config.Ports, Config.TLS, and the suppression/cache config without
+// dropping existing connections.
+func (app *App) listenForShutdownSignals() {
+	shutdownSig := make(chan os.Signal, 1)
+	signal.Notify(shutdownSig, os.Interrupt, syscall.SIGTERM)
+
+	reloadSig := make(chan os.Signal, 1)
+	signal.Notify(reloadSig, syscall.SIGHUP)
 
-		for _, server := range app.Servers {
-			if err := server.Shutdown(ctx); err != nil {
-				logger.Errorf("error shutting down server: %s", err)
+	go func() {
+		for {
+			select {
+			case <-shutdownSig:
+				os.Exit(app.shutdown())
+				return
+			case <-reloadSig:
+				if err := app.reloadConfig(); err != nil {
+					logger.Errorf("error reloading config: %s", err)
+				}
 			}
 		}
+	}()
+}
+
+// shutdown cancels app.ctx (unblocking any in-flight LLM calls), drains
+// in-flight requests up to the configured deadline, and shuts down every
+// server. It returns the process exit code: 0 if the drain completed
+// cleanly, 1 if the deadline was hit and servers were force-closed.
+func (app *App) shutdown() int {
+	logger.Infof("received shutdown signal. draining in-flight requests...")
+	app.cancel()
+
+	drainTimeout := app.Config.ShutdownDrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = defaultShutdownDrainTimeout
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
 
-		logger.Infoln("all servers shut down gracefully.")
-		os.Exit(0)
+	drained := make(chan struct{})
+	go func() {
+		app.inFlight.Wait()
+		close(drained)
 	}()
+
+	reportTicker := time.NewTicker(time.Second)
+	defer reportTicker.Stop()
+
+	forced := false
+	for {
+		select {
+		case <-drained:
+			forced = app.closeAllServers(shutdownCtx)
+			app.closeSinks()
+			if !forced {
+				logger.Infoln("all requests drained; servers shut down gracefully.")
+				return 0
+			}
+			logger.Errorf("one or more servers failed to shut down cleanly; forced closed.")
+			return 1
+		case <-shutdownCtx.Done():
+			logger.Errorf("drain deadline (%s) exceeded with %d request(s) still in flight; forcing close.", drainTimeout, atomic.LoadInt64(&app.inFlightCount))
+			app.closeAllServers(shutdownCtx)
+			app.closeSinks()
+			return 1
+		case <-reportTicker.C:
+			logger.Infof("%d request(s) still draining...", atomic.LoadInt64(&app.inFlightCount))
+		}
+	}
+}
+
+// closeSinks flushes and stops the event sinks (the OTLP batching worker and
+// the suppression sampler's background goroutine) so nothing queued is lost
+// when the process exits.
+func (app *App) closeSinks() {
+	if app.Logger != nil {
+		app.Logger.Close()
+	}
+	if app.Suppressor != nil {
+		app.Suppressor.Close()
+	}
+}
+
+// closeAllServers calls Shutdown on every server, falling back to a hard
+// Close for any that don't shut down within ctx. It reports whether any
+// server had to be force-closed.
+func (app *App) closeAllServers(ctx context.Context) bool {
+	forced := false
+	for port, server := range app.Servers {
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Errorf("error shutting down server on port %d: %s; forcing close", port, err)
+			server.Close()
+			forced = true
+		}
+	}
+	return forced
+}
+
+// reloadConfig re-reads the config file and diffs the new port set against
+// app.Servers: removed ports are shut down, added ports are started via the
+// same errgroup pattern startServers uses, and the TLS and cache config are
+// swapped in for subsequent requests. The suppression config is rebuilt into
+// a new *suppress.Suppressor that takes effect immediately, for both
+// Logger.allowed() and any server started afterwards; servers already
+// running keep their existing ErrorLog writer, same as existing connections
+// on unchanged ports are left untouched.
+func (app *App) reloadConfig() error {
+	logger.Infof("received SIGHUP. reloading config...")
+
+	newConfig, err := loadConfig(app.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	newPorts := make(map[uint16]PortConfig, len(newConfig.Ports))
+	for _, pc := range newConfig.Ports {
+		newPorts[pc.Port] = pc
+	}
+
+	app.serversMu.Lock()
+	var removed []uint16
+	for port := range app.Servers {
+		if _, ok := newPorts[port]; !ok {
+			removed = append(removed, port)
+		}
+	}
+	var added []PortConfig
+	for port, pc := range newPorts {
+		if _, ok := app.Servers[port]; !ok {
+			added = append(added, pc)
+		}
+	}
+	app.serversMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownDrainTimeout)
+	defer cancel()
+
+	for _, port := range removed {
+		app.serversMu.Lock()
+		server := app.Servers[port]
+		delete(app.Servers, port)
+		app.serversMu.Unlock()
+
+		logger.Infof("port %d removed from config, shutting it down", port)
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Errorf("error shutting down removed port %d: %s; forcing close", port, err)
+			server.Close()
+		}
+	}
+
+	newSuppressor, err := suppress.New(newConfig.Suppress, app.Logger.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild suppressor: %w", err)
+	}
+
+	app.suppressorMu.Lock()
+	oldSuppressor := app.Suppressor
+	app.Suppressor = newSuppressor
+	app.suppressorMu.Unlock()
+	app.Logger.SetSuppressor(newSuppressor)
+	if oldSuppressor != nil {
+		oldSuppressor.Close()
+	}
+
+	app.Config.TLS = newConfig.TLS
+	app.Config.Suppress = newConfig.Suppress
+	app.Config.Cache = newConfig.Cache
+
+	if len(added) > 0 {
+		// startServerSet blocks until the new servers exit, so it runs in its
+		// own goroutine to keep the signal loop free for later signals.
+		go func() {
+			if err := app.startServerSet(added); err != nil {
+				logger.Errorf("failed to start new ports: %s", err)
+			}
+		}()
+	}
+
+	logger.Infoln("config reload complete.")
+	return nil
 }
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// streamingProviders are the LLM providers known to support SSE/streaming
+// completions. Anything else falls back to the blocking generateLLMResponse
+// path, even if Config.Streaming is enabled.
+var streamingProviders = map[string]bool{
+	"openai":    true,
+	"anthropic": true,
+	"ollama":    true,
+}
+
+const (
+	// streamChunkDelayMin/Max bound the randomized delay inserted between
+	// flushed body chunks, so a streamed response doesn't look suspiciously
+	// uniform next to a real, jittery origin server.
+	streamChunkDelayMin = 5 * time.Millisecond
+	streamChunkDelayMax = 40 * time.Millisecond
+	streamChunkSize     = 24 // bytes of body flushed per write
+)
+
+// llmDelta is a single incremental chunk from a streaming LLM completion.
+// The channel is closed once the response is complete; Err is set if the
+// stream failed partway through.
+type llmDelta struct {
+	Text string
+	Err  error
+}
+
+func (app *App) supportsStreaming() bool {
+	return app.Config.Streaming && streamingProviders[app.Config.LLM.Provider]
+}
+
+// streamLLMResponse streams a generated HTTP response to the client as soon
+// as the headers are known, rather than blocking on the full JSON payload -
+// a blocking 10s LLM latency would otherwise be a 10s TTFB an attacker could
+// use to fingerprint the honeypot. It flushes body bytes in small,
+// randomly-delayed chunks to mimic a slow, jittery origin.
+//
+// On success the full assembled JSON is cached under key, same as the
+// non-streaming path. On a mid-stream failure, whatever was assembled so far
+// is cached under "partial:{key}" so a subsequent request with the same key
+// can replay the deterministic prefix while only the remaining tail is
+// re-requested from the LLM.
+//
+// The returned bool reports whether a response status/body was ever written
+// to w, so the caller can still fall back to http.Error on failure when it's
+// false (nothing written yet) without risking a corrupt response when it's
+// true (headers or body chunks already flushed).
+func (app *App) streamLLMResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, port, key string) ([]byte, bool, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false, fmt.Errorf("streaming unsupported: ResponseWriter is not a Flusher")
+	}
+
+	prompt := llmPrompt(r)
+	var prefix []byte
+	if partial, err := app.getCachedResponse("partial:" + key); err == nil {
+		prompt = resumePrompt(prompt, partial)
+		prefix = partial
+	}
+
+	deltas, err := app.streamLLMScript(ctx, prompt)
+	if err != nil {
+		return nil, false, fmt.Errorf("starting LLM stream: %w", err)
+	}
+
+	// Replay the prefix from a prior failed attempt so both the client and
+	// the assembled response start with the bytes already emitted.
+	var buf bytes.Buffer
+	parser := newResponseStreamParser()
+	buf.Write(prefix)
+	parser.feed(string(prefix))
+
+	headersSent := false
+	bodyFlushed := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			app.cachePartial(key, buf.Bytes())
+			return nil, headersSent, ctx.Err()
+		case d, open := <-deltas:
+			if !open {
+				raw := buf.Bytes()
+				app.finishStream(w, flusher, parser, key, raw, headersSent, bodyFlushed)
+				return raw, true, nil
+			}
+			if d.Err != nil {
+				app.cachePartial(key, buf.Bytes())
+				return nil, headersSent, fmt.Errorf("LLM stream error: %w", d.Err)
+			}
+
+			buf.WriteString(d.Text)
+			parser.feed(d.Text)
+
+			if !headersSent && parser.headersReady() {
+				for k, v := range parser.headers() {
+					if !isExcludedHeader(k) {
+						w.Header().Set(k, v)
+					}
+				}
+				w.WriteHeader(http.StatusOK)
+				flusher.Flush()
+				headersSent = true
+			}
+
+			if headersSent {
+				bodyFlushed += app.flushBodyChunks(w, flusher, parser, bodyFlushed)
+			}
+		}
+	}
+}
+
+// finishStream flushes any remaining body bytes and caches the full
+// assembled response under key.
+func (app *App) finishStream(w http.ResponseWriter, flusher http.Flusher, parser *responseStreamParser, key string, raw []byte, headersSent bool, bodyFlushed int) {
+	if !headersSent {
+		// The stream ended before we ever saw a complete headers object;
+		// this is effectively a failure, but give the client whatever we
+		// have rather than hanging.
+		for k, v := range parser.headers() {
+			if !isExcludedHeader(k) {
+				w.Header().Set(k, v)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	app.flushBodyChunks(w, flusher, parser, bodyFlushed)
+	flusher.Flush()
+
+	if err := app.storeResponse(key, raw); err != nil {
+		logger.Errorf("error caching streamed response for %q: %s", key, err)
+	}
+}
+
+// flushBodyChunks writes newly-available body bytes (beyond what's already
+// been flushed) to w in small chunks with a randomized delay between each,
+// and returns how many bytes were flushed.
+func (app *App) flushBodyChunks(w http.ResponseWriter, flusher http.Flusher, parser *responseStreamParser, alreadyFlushed int) int {
+	body := parser.body()
+	if len(body) <= alreadyFlushed {
+		return 0
+	}
+
+	pending := body[alreadyFlushed:]
+	written := 0
+	for len(pending) > 0 {
+		n := streamChunkSize
+		if n > len(pending) {
+			n = len(pending)
+		}
+		w.Write([]byte(pending[:n]))
+		flusher.Flush()
+		written += n
+		pending = pending[n:]
+
+		if len(pending) > 0 {
+			time.Sleep(randomDelay(streamChunkDelayMin, streamChunkDelayMax))
+		}
+	}
+
+	parser.flushedBody += written
+	return written
+}
+
+func randomDelay(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+func (app *App) cachePartial(key string, raw []byte) {
+	if len(raw) == 0 {
+		return
+	}
+	if err := app.storeResponse("partial:"+key, raw); err != nil {
+		logger.Errorf("error caching partial response for %q: %s", key, err)
+	}
+}
+
+// resumePrompt extends prompt with the bytes already emitted for a prior,
+// failed attempt at the same cache key, so the LLM only needs to produce the
+// remaining tail and the deterministic prefix can be replayed verbatim.
+func resumePrompt(prompt string, partial []byte) string {
+	return fmt.Sprintf(
+		"%s\n\nA previous attempt at this exact response was cut short after emitting the following "+
+			"bytes. Continue from exactly where it left off; do not repeat or re-emit anything already "+
+			"shown:\n\n%s", prompt, string(partial),
+	)
+}
+
+// responseStreamParser incrementally extracts the "headers" object and
+// "body" string out of a streamed {"headers": {...}, "body": "..."} JSON
+// payload as bytes arrive, without needing the full document to be valid
+// JSON yet.
+type responseStreamParser struct {
+	buf         bytes.Buffer
+	flushedBody int
+
+	headersDone bool
+	parsedHeads map[string]string
+
+	bodyStart int // index into buf.Bytes() where the body string content begins, -1 if not found yet
+}
+
+func newResponseStreamParser() *responseStreamParser {
+	return &responseStreamParser{bodyStart: -1}
+}
+
+func (p *responseStreamParser) feed(s string) {
+	p.buf.WriteString(s)
+}
+
+// headersReady reports whether a complete, balanced "headers" JSON object
+// has been seen yet, parsing and caching it on first success.
+func (p *responseStreamParser) headersReady() bool {
+	if p.headersDone {
+		return true
+	}
+
+	data := p.buf.Bytes()
+	idx := bytes.Index(data, []byte(`"headers"`))
+	if idx == -1 {
+		return false
+	}
+	start := bytes.IndexByte(data[idx:], '{')
+	if start == -1 {
+		return false
+	}
+	start += idx
+
+	depth := 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				var headers map[string]string
+				if err := json.Unmarshal(data[start:i+1], &headers); err != nil {
+					return false
+				}
+				p.parsedHeads = headers
+				p.headersDone = true
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *responseStreamParser) headers() map[string]string {
+	return p.parsedHeads
+}
+
+// body returns the JSON-unescaped body content decoded so far. It's
+// recomputed on each call, which is fine at the (small) scale of a single
+// honeypot response.
+func (p *responseStreamParser) body() string {
+	data := p.buf.Bytes()
+	if p.bodyStart == -1 {
+		idx := bytes.Index(data, []byte(`"body"`))
+		if idx == -1 {
+			return ""
+		}
+		q := bytes.IndexByte(data[idx:], ':')
+		if q == -1 {
+			return ""
+		}
+		q += idx
+		quote := bytes.IndexByte(data[q:], '"')
+		if quote == -1 {
+			return ""
+		}
+		p.bodyStart = q + quote + 1
+	}
+
+	if p.bodyStart >= len(data) {
+		return ""
+	}
+
+	end := findUnescapedQuote(data[p.bodyStart:])
+	var raw []byte
+	if end == -1 {
+		raw = data[p.bodyStart:]
+	} else {
+		raw = data[p.bodyStart : p.bodyStart+end]
+	}
+
+	var out string
+	// Best-effort unescape by round-tripping through the JSON decoder with a
+	// synthetic closing quote; an unterminated escape sequence at the tail
+	// (still arriving) is simply held back until the next call.
+	if err := json.Unmarshal(append(append([]byte{'"'}, raw...), '"'), &out); err != nil {
+		return out
+	}
+	return out
+}
+
+func findUnescapedQuote(data []byte) int {
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\\' {
+			i++
+			continue
+		}
+		if data[i] == '"' {
+			return i
+		}
+	}
+	return -1
+}
+
+func llmPrompt(r *http.Request) string {
+	return fmt.Sprintf("%s %s %s", r.Method, r.RequestURI, r.Proto)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	autogenCAFile   = "ca.pem"
+	autogenCAKey    = "ca.key"
+	autogenValidFor = 825 * 24 * time.Hour // validity of both the CA and each leaf cert; an expired CA on disk is not replaced
+)
+
+// autogenCA is a persistent, on-disk CA used to sign in-memory leaf certs for
+// TLS profiles marked Autogen. It is created once under Config.TLSAutogenDir
+// and reused across restarts so JA3/JA4 fingerprints stay stable.
+type autogenCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// autogenState caches the CA and the per-profile leaf certs generated from
+// it, keyed by TLS profile name, so a profile's cert (and therefore its
+// deterministic serial number) is only computed once per process.
+type autogenState struct {
+	mu    sync.Mutex
+	dir   string
+	ca    *autogenCA
+	certs map[string]*tls.Certificate
+}
+
+func newAutogenState(dir string) *autogenState {
+	return &autogenState{dir: dir, certs: make(map[string]*tls.Certificate)}
+}
+
+// certForProfile returns the in-memory leaf certificate for the given TLS
+// profile, generating (and caching) it on first use.
+func (a *autogenState) certForProfile(name string, tp TLSProfile) (*tls.Certificate, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cert, ok := a.certs[name]; ok {
+		return cert, nil
+	}
+
+	ca, err := a.loadOrCreateCA()
+	if err != nil {
+		return nil, fmt.Errorf("autogen CA: %w", err)
+	}
+
+	cert, err := generateLeafCert(ca, name, tp)
+	if err != nil {
+		return nil, fmt.Errorf("autogen leaf cert for profile %q: %w", name, err)
+	}
+
+	a.certs[name] = cert
+	return cert, nil
+}
+
+func (a *autogenState) loadOrCreateCA() (*autogenCA, error) {
+	if a.ca != nil {
+		return a.ca, nil
+	}
+
+	certPath := filepath.Join(a.dir, autogenCAFile)
+	keyPath := filepath.Join(a.dir, autogenCAKey)
+
+	if ca, err := loadCA(certPath, keyPath); err == nil {
+		a.ca = ca
+		return ca, nil
+	}
+
+	ca, err := createCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(a.dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating autogen dir: %w", err)
+	}
+	if err := saveCA(ca, certPath, keyPath); err != nil {
+		return nil, err
+	}
+
+	a.ca = ca
+	return ca, nil
+}
+
+func loadCA(certPath, keyPath string) (*autogenCA, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("invalid CA certificate PEM at %s", certPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("invalid CA key PEM at %s", keyPath)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CA key: %w", err)
+	}
+
+	return &autogenCA{cert: cert, key: key}, nil
+}
+
+func createCA() (*autogenCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating CA key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating CA serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "galah autogen CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(autogenValidFor),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing freshly created CA certificate: %w", err)
+	}
+
+	return &autogenCA{cert: cert, key: key}, nil
+}
+
+func saveCA(ca *autogenCA, certPath, keyPath string) error {
+	keyBytes, err := x509.MarshalECPrivateKey(ca.key)
+	if err != nil {
+		return fmt.Errorf("marshaling CA key: %w", err)
+	}
+
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0644); err != nil {
+		return fmt.Errorf("writing CA certificate: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600); err != nil {
+		return fmt.Errorf("writing CA key: %w", err)
+	}
+
+	return nil
+}
+
+// deterministicLeafKey derives an ECDSA P-256 key pair from the CA key and
+// profile name via HKDF-SHA256, so a given profile always gets the same leaf
+// key (and therefore the same serial, derived from it below) across restarts
+// without needing to persist per-profile key material to disk the way
+// loadOrCreateCA/saveCA do for the CA itself.
+func deterministicLeafKey(ca *autogenCA, profileName string) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	r := hkdf.New(sha256.New, ca.key.D.Bytes(), nil, []byte("galah autogen leaf:"+profileName))
+
+	// Oversample relative to the 32-byte curve order to keep the mod-N
+	// reduction bias negligible.
+	buf := make([]byte, 48)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("deriving leaf key material: %w", err)
+	}
+	d := new(big.Int).SetBytes(buf)
+	d.Mod(d, new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	key := new(ecdsa.PrivateKey)
+	key.Curve = curve
+	key.D = d
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	return key, nil
+}
+
+// generateLeafCert signs an ECDSA P-256 leaf certificate for the given TLS
+// profile. Both the leaf key and the serial number are derived
+// deterministically from the profile name (and the persistent CA key), so
+// the same profile produces the same key and serial across restarts, which
+// is handy for JA3/JA4 research and reproducing fingerprints seen in scans.
+func generateLeafCert(ca *autogenCA, profileName string, tp TLSProfile) (*tls.Certificate, error) {
+	key, err := deterministicLeafKey(ca, profileName)
+	if err != nil {
+		return nil, fmt.Errorf("generating leaf key: %w", err)
+	}
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling leaf public key: %w", err)
+	}
+	h := sha256.Sum256(append([]byte(profileName), pubBytes...))
+	serial := new(big.Int).SetBytes(h[:16])
+
+	sans, err := buildSANs(tp)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: profileName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(autogenValidFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     sans.dnsNames,
+		IPAddresses:  sans.ipAddresses,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("signing leaf certificate: %w", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        nil,
+	}, nil
+}
+
+type sanSet struct {
+	dnsNames    []string
+	ipAddresses []net.IP
+}
+
+func buildSANs(tp TLSProfile) (sanSet, error) {
+	set := sanSet{
+		dnsNames:    append([]string{"localhost"}, tp.DNSNames...),
+		ipAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	for _, ip := range tp.IPAddresses {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			set.ipAddresses = append(set.ipAddresses, parsed)
+		}
+	}
+
+	if outboundIP, err := outboundIP(); err == nil {
+		set.ipAddresses = append(set.ipAddresses, outboundIP)
+	}
+
+	return set, nil
+}
+
+// outboundIP auto-detects the host's outbound IP address using the classic
+// UDP dial trick: no packet is actually sent, but the OS picks the route and
+// therefore the source address we'd use to reach the internet.
+func outboundIP() (net.IP, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// wsStep is a single scripted frame in a WebSocket session, as generated by
+// the LLM from the upgrade request and chosen subprotocol.
+type wsStep struct {
+	Direction string `json:"direction"` // "send" or "expect"
+	Opcode    string `json:"opcode"`    // "text", "binary", "ping", "close"
+	Payload   string `json:"payload"`
+	DelayMs   int    `json:"delay_ms"`
+	CloseCode int    `json:"close_code"`
+}
+
+// wsScript is the cached, ordered list of steps that drives a WebSocket
+// session once the handshake completes.
+type wsScript struct {
+	Steps []wsStep `json:"steps"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	// The honeypot accepts whatever origin a scanner sends; we aren't
+	// protecting a real browser client here, we're impersonating a server.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// isWebSocketUpgrade reports whether r is a WebSocket handshake request.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		r.Header.Get("Sec-WebSocket-Key") != ""
+}
+
+func headerContainsToken(h http.Header, key, token string) bool {
+	for _, v := range strings.Split(h.Get(key), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWebSocket completes the WebSocket handshake and then drives the
+// connection from an LLM-generated frame script, logging each frame through
+// the existing commonFields pipeline under a single session ID.
+func (app *App) handleWebSocket(w http.ResponseWriter, r *http.Request, port string) {
+	subprotocol := r.Header.Get("Sec-WebSocket-Protocol")
+
+	key := app.wsCacheKey(r, subprotocol)
+	script, source, err := app.getWSScript(r.Context(), r, key, subprotocol)
+	if err != nil {
+		logger.Errorf("error generating websocket script: %s", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var responseHeader http.Header
+	if subprotocol != "" {
+		responseHeader = http.Header{"Sec-WebSocket-Protocol": []string{subprotocol}}
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		logger.Errorf("error upgrading websocket connection: %s", err)
+		return
+	}
+	defer conn.Close()
+
+	sessionID := uuid.NewString()
+	logger.Infof("websocket session %s established on port %s (subprotocol=%q, source=%s)", sessionID, port, subprotocol, source)
+
+	app.installWSControlHandlers(conn, r, port, sessionID)
+	app.runWSScript(r.Context(), conn, script, r, port, sessionID)
+}
+
+// installWSControlHandlers logs ping and close control frames the moment
+// gorilla/websocket receives them, then replies the way the library's
+// default handlers do (pong the ping, echo the close code back). Without
+// this, Conn.ReadMessage never surfaces control frames at all - pings are
+// answered transparently and a close only shows up as a *websocket.CloseError
+// from ReadMessage - so expectWSFrame has no chance to log them itself.
+func (app *App) installWSControlHandlers(conn *websocket.Conn, r *http.Request, port, sessionID string) {
+	conn.SetPingHandler(func(appData string) error {
+		app.Logger.LogWSFrame(r, port, sessionID, "recv", "ping", []byte(appData))
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(5*time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	conn.SetCloseHandler(func(code int, text string) error {
+		app.Logger.LogWSFrame(r, port, sessionID, "recv", "close", []byte(text))
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(5*time.Second))
+		return nil
+	})
+}
+
+// generateWSScript asks the LLM for a plausible frame script given the
+// upgrade request and chosen subprotocol, following the same provider/model
+// configured for regular HTTP responses.
+func (app *App) generateWSScript(ctx context.Context, r *http.Request, subprotocol string) (string, error) {
+	prompt := fmt.Sprintf(
+		"You are simulating a WebSocket server for a honeypot. Given the following HTTP upgrade "+
+			"request and negotiated subprotocol %q, respond with ONLY a JSON object of the form "+
+			`{"steps":[{"direction":"send"|"expect","opcode":"text"|"binary"|"ping"|"close","payload":"...","delay_ms":N,"close_code":N}]}. `+
+			"Produce plausible traffic for the subprotocol (chat, MQTT-over-WS, Socket.IO, or a generic echo "+
+			"service if the subprotocol is empty or unrecognized).\n\nUpgrade request:\n%s %s %s\n%s",
+		subprotocol, r.Method, r.RequestURI, r.Proto, formatHeaders(r.Header),
+	)
+
+	return app.generateLLMScript(ctx, prompt)
+}
+
+func formatHeaders(h http.Header) string {
+	var b strings.Builder
+	for k, v := range h {
+		fmt.Fprintf(&b, "%s: %s\n", k, strings.Join(v, ", "))
+	}
+	return b.String()
+}
+
+func (app *App) wsCacheKey(r *http.Request, subprotocol string) string {
+	srcClass := ""
+	if info, err := app.EnrichCache.Process(remoteHost(r)); err == nil && info != nil {
+		srcClass = info.KnownScanner
+	}
+	hash := sha256.Sum256([]byte(subprotocol + "|" + srcClass))
+	return "ws:" + hex.EncodeToString(hash[:])
+}
+
+func remoteHost(r *http.Request) string {
+	host := r.RemoteAddr
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[:i]
+	}
+	return host
+}
+
+// getWSScript returns the cached script for key, generating (and caching) a
+// new one from the LLM if there's no cache hit.
+func (app *App) getWSScript(ctx context.Context, r *http.Request, key, subprotocol string) (wsScript, string, error) {
+	if cached, err := app.getCachedResponse(key); err == nil {
+		var script wsScript
+		if err := json.Unmarshal(cached, &script); err == nil {
+			return script, "cache", nil
+		}
+	}
+
+	raw, err := app.generateWSScript(ctx, r, subprotocol)
+	if err != nil {
+		return wsScript{}, "", fmt.Errorf("generating websocket script: %w", err)
+	}
+
+	var script wsScript
+	if err := json.Unmarshal([]byte(raw), &script); err != nil {
+		return wsScript{}, "", fmt.Errorf("invalid websocket script from LLM: %w", err)
+	}
+
+	if err := app.storeResponse(key, []byte(raw)); err != nil {
+		logger.Errorf("error caching websocket script for %q: %s", key, err)
+	}
+
+	return script, "llm", nil
+}
+
+// runWSScript drives conn through each scripted step in order, logging every
+// frame as its own event tagged with sessionID so a full session is
+// reconstructable from the event log.
+func (app *App) runWSScript(ctx context.Context, conn *websocket.Conn, script wsScript, r *http.Request, port, sessionID string) {
+	for _, step := range script.Steps {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if step.DelayMs > 0 {
+			time.Sleep(time.Duration(step.DelayMs) * time.Millisecond)
+		}
+
+		switch step.Direction {
+		case "send":
+			app.sendWSFrame(conn, step, r, port, sessionID)
+		case "expect":
+			app.expectWSFrame(conn, step, r, port, sessionID)
+		default:
+			logger.Errorf("websocket session %s: unknown step direction %q", sessionID, step.Direction)
+		}
+	}
+}
+
+func (app *App) sendWSFrame(conn *websocket.Conn, step wsStep, r *http.Request, port, sessionID string) {
+	opcode, payload := wsOpcode(step)
+
+	var err error
+	switch opcode {
+	case websocket.CloseMessage:
+		err = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(step.CloseCode, step.Payload), time.Now().Add(5*time.Second))
+	case websocket.PingMessage:
+		err = conn.WriteControl(websocket.PingMessage, payload, time.Now().Add(5*time.Second))
+	default:
+		err = conn.WriteMessage(opcode, payload)
+	}
+
+	if err != nil {
+		logger.Errorf("websocket session %s: error sending frame: %s", sessionID, err)
+		return
+	}
+
+	app.Logger.LogWSFrame(r, port, sessionID, "send", step.Opcode, payload)
+}
+
+func (app *App) expectWSFrame(conn *websocket.Conn, step wsStep, r *http.Request, port, sessionID string) {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	opcode, payload, err := conn.ReadMessage()
+	if err != nil {
+		if _, ok := err.(*websocket.CloseError); ok {
+			// Already logged by the close handler installed in
+			// installWSControlHandlers.
+			return
+		}
+		logger.Infof("websocket session %s: no frame received while expecting %s: %s", sessionID, step.Opcode, err)
+		return
+	}
+
+	app.Logger.LogWSFrame(r, port, sessionID, "recv", wsOpcodeName(opcode), payload)
+}
+
+func wsOpcode(step wsStep) (int, []byte) {
+	switch step.Opcode {
+	case "binary":
+		return websocket.BinaryMessage, []byte(step.Payload)
+	case "ping":
+		return websocket.PingMessage, []byte(step.Payload)
+	case "close":
+		return websocket.CloseMessage, []byte(step.Payload)
+	default:
+		return websocket.TextMessage, []byte(step.Payload)
+	}
+}
+
+func wsOpcodeName(opcode int) string {
+	switch opcode {
+	case websocket.BinaryMessage:
+		return "binary"
+	case websocket.PingMessage:
+		return "ping"
+	case websocket.PongMessage:
+		return "pong"
+	case websocket.CloseMessage:
+		return "close"
+	default:
+		return "text"
+	}
+}